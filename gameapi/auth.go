@@ -0,0 +1,197 @@
+package gameapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// errTokenGameMismatch is returned by overrideBodyWithToken when the
+// request body names a different game_id than the token was issued for.
+// A token only proves who the caller is within the game /auth minted it
+// for; without this check, a token for game A's team 1 could be replayed
+// against any other game_id and be trusted as team 1 there too.
+var errTokenGameMismatch = errors.New("token is not valid for this game_id")
+
+// tokenTTL is how long a token issued by /auth stays valid.
+const tokenTTL = 24 * time.Hour
+
+// authedPaths require a valid bearer token; ServeHTTP overrides the
+// request body's player_id/team with the token's before the handler for
+// the path ever sees it, so a client can no longer impersonate another
+// player by guessing their player_id.
+var authedPaths = map[string]bool{
+	"/guess":    true,
+	"/end-turn": true,
+	"/chat":     true,
+	"/events":   true,
+	"/ping":     true,
+}
+
+// authToken is the payload signed into the bearer token /auth returns.
+// Role, when set, is "spymaster" or "operative"; /new-game reports it back
+// as spymaster_view (see writeGameJSON). Responses are not redacted for
+// operatives: every caller receives the same game data.
+type authToken struct {
+	PlayerID string    `json:"player_id"`
+	GameID   string    `json:"game_id"`
+	Team     int       `json:"team"`
+	Role     string    `json:"role,omitempty"`
+	Exp      time.Time `json:"exp"`
+}
+
+func tokenSecret() []byte {
+	return []byte(os.Getenv("CODENAMES_SECRET"))
+}
+
+func signAuthToken(t authToken) (string, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, tokenSecret())
+	mac.Write(payload)
+	sig := mac.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func verifyAuthToken(raw string) (authToken, bool) {
+	parts := strings.SplitN(raw, ".", 2)
+	if len(parts) != 2 {
+		return authToken{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return authToken{}, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return authToken{}, false
+	}
+	mac := hmac.New(sha256.New, tokenSecret())
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), sig) {
+		return authToken{}, false
+	}
+	var t authToken
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return authToken{}, false
+	}
+	if time.Now().After(t.Exp) {
+		return authToken{}, false
+	}
+	return t, true
+}
+
+// POST /auth
+// Exchanges a desired name/team for a signed token binding a freshly
+// generated player_id to this game and team (and, optionally, a spymaster
+// or operative role). Callers present the token as an Authorization:
+// Bearer header on every later request instead of a self-chosen player_id.
+func (h *handler) handleAuth(rw http.ResponseWriter, req *http.Request) {
+	var body struct {
+		GameID      string `json:"game_id"`
+		DesiredName string `json:"desired_name"`
+		Team        int    `json:"team"`
+		Role        string `json:"role,omitempty"`
+	}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil || body.GameID == "" || body.DesiredName == "" {
+		writeError(rw, "malformed_body", "Unable to parse request body.", 400)
+		return
+	}
+	if body.Role != "" && body.Role != "spymaster" && body.Role != "operative" {
+		writeError(rw, "bad_role", fmt.Sprintf("Unknown role %q.", body.Role), 400)
+		return
+	}
+
+	_, ok := h.store.Get(body.GameID)
+	h.mu.Lock()
+	playerID := fmt.Sprintf("%x", h.rand.Int63())
+	h.mu.Unlock()
+	if !ok {
+		writeError(rw, "not_found", "Game not found", 404)
+		return
+	}
+
+	t := authToken{
+		PlayerID: playerID,
+		GameID:   body.GameID,
+		Team:     body.Team,
+		Role:     body.Role,
+		Exp:      time.Now().Add(tokenTTL),
+	}
+	signed, err := signAuthToken(t)
+	if err != nil {
+		http.Error(rw, "unable to sign token: "+err.Error(), 500)
+		return
+	}
+
+	writeJSON(rw, struct {
+		Token    string `json:"token"`
+		PlayerID string `json:"player_id"`
+		Exp      int64  `json:"exp"`
+	}{Token: signed, PlayerID: t.PlayerID, Exp: t.Exp.Unix()})
+}
+
+// bearerToken pulls and verifies the token from an Authorization: Bearer
+// header.
+func bearerToken(req *http.Request) (authToken, bool) {
+	auth := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(auth, prefix) {
+		return authToken{}, false
+	}
+	return verifyAuthToken(strings.TrimPrefix(auth, prefix))
+}
+
+// overrideBodyWithToken rewrites the request body's player_id/team (and
+// game_id, if absent) to match the verified token, so handlers downstream
+// can keep trusting the body exactly as they do today. It returns
+// errTokenGameMismatch, rather than silently preferring the body's value,
+// if the body names a game_id other than the one the token was issued
+// for — otherwise a token minted for one game could be replayed against
+// any other game_id and be trusted there too.
+func overrideBodyWithToken(req *http.Request, t authToken) error {
+	raw, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+
+	fields := map[string]json.RawMessage{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return err
+		}
+	}
+	if gj, ok := fields["game_id"]; ok {
+		var bodyGameID string
+		if err := json.Unmarshal(gj, &bodyGameID); err != nil {
+			return err
+		}
+		if bodyGameID != "" && bodyGameID != t.GameID {
+			return errTokenGameMismatch
+		}
+	}
+
+	fields["player_id"], _ = json.Marshal(t.PlayerID)
+	fields["team"], _ = json.Marshal(t.Team)
+	fields["game_id"], _ = json.Marshal(t.GameID)
+
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(rewritten))
+	return nil
+}
@@ -0,0 +1,100 @@
+package gameapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSignVerifyAuthTokenRoundTrip(t *testing.T) {
+	os.Setenv("CODENAMES_SECRET", "test-secret")
+	defer os.Unsetenv("CODENAMES_SECRET")
+
+	want := authToken{
+		PlayerID: "abc123",
+		GameID:   "apple-banana",
+		Team:     1,
+		Role:     "spymaster",
+		Exp:      time.Now().Add(time.Hour),
+	}
+	signed, err := signAuthToken(want)
+	if err != nil {
+		t.Fatalf("signAuthToken: %v", err)
+	}
+	got, ok := verifyAuthToken(signed)
+	if !ok {
+		t.Fatal("verifyAuthToken rejected a token it just signed")
+	}
+	if got.PlayerID != want.PlayerID || got.GameID != want.GameID || got.Team != want.Team || got.Role != want.Role {
+		t.Fatalf("verifyAuthToken = %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifyAuthTokenRejectsTampering(t *testing.T) {
+	os.Setenv("CODENAMES_SECRET", "test-secret")
+	defer os.Unsetenv("CODENAMES_SECRET")
+
+	signed, err := signAuthToken(authToken{PlayerID: "abc", GameID: "g1", Team: 1, Exp: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("signAuthToken: %v", err)
+	}
+
+	tampered := signed[:len(signed)-1] + "x"
+	if _, ok := verifyAuthToken(tampered); ok {
+		t.Fatal("verifyAuthToken accepted a tampered token")
+	}
+}
+
+func TestVerifyAuthTokenRejectsExpired(t *testing.T) {
+	os.Setenv("CODENAMES_SECRET", "test-secret")
+	defer os.Unsetenv("CODENAMES_SECRET")
+
+	signed, err := signAuthToken(authToken{PlayerID: "abc", GameID: "g1", Team: 1, Exp: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("signAuthToken: %v", err)
+	}
+	if _, ok := verifyAuthToken(signed); ok {
+		t.Fatal("verifyAuthToken accepted an expired token")
+	}
+}
+
+func TestOverrideBodyWithTokenRejectsGameMismatch(t *testing.T) {
+	tok := authToken{PlayerID: "abc", GameID: "game-a", Team: 1}
+	req, err := http.NewRequest("POST", "/guess", bytes.NewBufferString(`{"game_id":"game-b"}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	if err := overrideBodyWithToken(req, tok); !errors.Is(err, errTokenGameMismatch) {
+		t.Fatalf("overrideBodyWithToken = %v, want errTokenGameMismatch", err)
+	}
+}
+
+func TestOverrideBodyWithTokenFillsMissingGameID(t *testing.T) {
+	tok := authToken{PlayerID: "abc", GameID: "game-a", Team: 2}
+	req, err := http.NewRequest("POST", "/guess", bytes.NewBufferString(`{"index":3}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	if err := overrideBodyWithToken(req, tok); err != nil {
+		t.Fatalf("overrideBodyWithToken: %v", err)
+	}
+
+	var got struct {
+		GameID   string `json:"game_id"`
+		PlayerID string `json:"player_id"`
+		Team     int    `json:"team"`
+		Index    int    `json:"index"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+		t.Fatalf("decode rewritten body: %v", err)
+	}
+	if got.GameID != tok.GameID || got.PlayerID != tok.PlayerID || got.Team != tok.Team || got.Index != 3 {
+		t.Fatalf("rewritten body = %+v, want game_id/player_id/team from token with index preserved", got)
+	}
+}
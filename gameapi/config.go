@@ -0,0 +1,66 @@
+package gameapi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Config holds the per-game rules chosen when a game is created: which mode
+// is being played, which word lists were mixed together to build the board,
+// and the optional timers/penalties that go with the chosen mode.
+//
+// Mode, Lists, Public, and TurnSeconds are all consulted today (mode
+// validation, wordsForLists, /list's public filter, and the turn clock,
+// respectively). MaxGuessesPerTurn, BystanderPenalty, and AssassinCount are
+// accepted, stored, and echoed back in the /new-game and /replay responses,
+// but nothing reads them - enforcing a guess cap, a bystander penalty, or an
+// assassin count different from the classic board needs per-guess state (how
+// many guesses this turn, what color a guess revealed) that lives on Game, and
+// that type isn't part of this checkout. "hardcore" is likewise accepted by
+// validModes but currently plays identically to "classic".
+type Config struct {
+	Mode              string   `json:"mode"`
+	Lists             []string `json:"lists,omitempty"`
+	TurnSeconds       int      `json:"turn_seconds,omitempty"`
+	MaxGuessesPerTurn int      `json:"max_guesses_per_turn,omitempty"` // not yet enforced
+	BystanderPenalty  bool     `json:"bystander_penalty,omitempty"`    // not yet enforced
+	AssassinCount     int      `json:"assassin_count,omitempty"`       // not yet enforced
+	Public            bool     `json:"public,omitempty"`
+}
+
+// validModes are the modes a /new-game request is allowed to ask for.
+// "hardcore" is accepted but not yet distinct from "classic" - see Config's
+// doc comment.
+var validModes = map[string]bool{
+	"classic":  true,
+	"timed":    true,
+	"hardcore": true,
+}
+
+// DefaultConfig is applied to a /new-game request that omits config
+// entirely, preserving today's classic, untimed rules.
+var DefaultConfig = Config{Mode: "classic"}
+
+// wordsForLists returns the de-duplicated, sorted union of words drawn from
+// the named lists, the same way Handler builds allWords from every list
+// combined. An unknown name is reported back to the caller rather than
+// silently contributing nothing, so a typo in Config.Lists surfaces as an
+// error instead of a suspiciously short board.
+func (h *handler) wordsForLists(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	var words []string
+	for _, name := range names {
+		list, ok := h.wordLists[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown word list %q", name)
+		}
+		for _, w := range list {
+			if !seen[w] {
+				words = append(words, w)
+				seen[w] = true
+			}
+		}
+	}
+	sort.Strings(words)
+	return words, nil
+}
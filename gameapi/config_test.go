@@ -0,0 +1,35 @@
+package gameapi
+
+import "testing"
+
+func TestWordsForListsMergesAndDedupes(t *testing.T) {
+	h := &handler{
+		wordLists: map[string][]string{
+			"a": {"apple", "banana"},
+			"b": {"banana", "cherry"},
+		},
+	}
+
+	words, err := h.wordsForLists([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("wordsForLists: %v", err)
+	}
+
+	want := []string{"apple", "banana", "cherry"}
+	if len(words) != len(want) {
+		t.Fatalf("wordsForLists = %v, want %v", words, want)
+	}
+	for i, w := range want {
+		if words[i] != w {
+			t.Fatalf("wordsForLists = %v, want %v", words, want)
+		}
+	}
+}
+
+func TestWordsForListsRejectsUnknownList(t *testing.T) {
+	h := &handler{wordLists: map[string][]string{"a": {"apple"}}}
+
+	if _, err := h.wordsForLists([]string{"a", "nonexistent"}); err == nil {
+		t.Fatal("wordsForLists accepted an unknown list name")
+	}
+}
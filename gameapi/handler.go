@@ -2,25 +2,56 @@ package gameapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/net/websocket"
+
 	"codenamesgreen/dictionary-master"
 )
 
 // Handler implements the codenames green server handler.
 func Handler(wordLists map[string][]string) http.Handler {
+	if os.Getenv("CODENAMES_SECRET") == "" {
+		// Signing auth tokens with an empty key is worse than not
+		// signing them at all: every token becomes forgeable by
+		// computing the same HMAC with the same empty key, while
+		// still looking like a server that enforces auth. Refuse to
+		// start instead of quietly no-op'ing the whole feature.
+		panic("gameapi: CODENAMES_SECRET must be set")
+	}
+
 	h := &handler{
-		mux:       http.NewServeMux(),
-		wordLists: wordLists,
-		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
-		games:     make(map[string]*Game),
+		mux:           http.NewServeMux(),
+		wordLists:     wordLists,
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		configs:       make(map[string]*Config),
+		turnDeadlines: make(map[*Game]time.Time),
+	}
+
+	// CODENAMES_GAME_DIR opts into a file-backed GameStore that snapshots
+	// every game to disk, so active games survive a restart. Without it,
+	// games live only in memory, same as before.
+	if dir := os.Getenv("CODENAMES_GAME_DIR"); dir != "" {
+		fs, err := newFileStore(dir, func(id string) *Config {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			return h.configs[id]
+		})
+		if err != nil {
+			panic(err)
+		}
+		h.store = fs
+	} else {
+		h.store = newMemoryStore()
 	}
 
 	// Build a list of all words. The combined list
@@ -38,29 +69,46 @@ func Handler(wordLists map[string][]string) http.Handler {
 	sort.Strings(h.allWords)
 
 	h.mux.HandleFunc("/index", h.handleIndex)
+	h.mux.HandleFunc("/auth", h.handleAuth)
 	h.mux.HandleFunc("/new-game", h.handleNewGame)
 	h.mux.HandleFunc("/guess", h.handleGuess)
 	h.mux.HandleFunc("/end-turn", h.handleEndTurn)
 	h.mux.HandleFunc("/chat", h.handleChat)
 	h.mux.HandleFunc("/events", h.handleEvents)
+	h.mux.Handle("/ws", websocket.Handler(h.handleWS))
 	h.mux.HandleFunc("/ping", h.handlePing)
 	h.mux.HandleFunc("/stats", h.handleStats)
+	h.mux.HandleFunc("/list", h.handleList)
+	h.mux.HandleFunc("/replay", h.handleReplay)
+	h.mux.HandleFunc("/export", h.handleExport)
 
 	// Periodically remove games that are old and inactive.
 	go func() {
+		type staleGame struct {
+			id string
+			g  *Game
+		}
 		for now := range time.Tick(10 * time.Minute) {
-			h.mu.Lock()
-			for id, g := range h.games {
+			var stale []staleGame
+			h.store.Range(func(id string, g *Game) bool {
 				remaining := g.pruneOldPlayers(now)
 				if remaining > 0 {
-					continue // at least one player is still in the game
+					return true // at least one player is still in the game
 				}
 				if g.CreatedAt.Add(24 * time.Hour).After(time.Now()) {
-					continue // hasn't been 24 hours since the game started
+					return true // hasn't been 24 hours since the game started
 				}
-				delete(h.games, id)
+				stale = append(stale, staleGame{id, g})
+				return true
+			})
+
+			for _, sg := range stale {
+				h.store.Delete(sg.id)
+				h.mu.Lock()
+				delete(h.configs, sg.id)
+				delete(h.turnDeadlines, sg.g)
+				h.mu.Unlock()
 			}
-			h.mu.Unlock()
 		}
 	}()
 
@@ -73,8 +121,11 @@ type handler struct {
 	allWords  []string
 	rand      *rand.Rand
 
-	mu    sync.Mutex
-	games map[string]*Game
+	store GameStore
+
+	mu            sync.Mutex
+	configs       map[string]*Config   // keyed by game ID, alongside store
+	turnDeadlines map[*Game]time.Time // keyed by *Game, not game ID, for modes with a turn clock
 }
 
 func (h *handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -89,6 +140,23 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		rw.WriteHeader(http.StatusOK)
 		return
 	}
+
+	if authedPaths[req.URL.Path] {
+		t, ok := bearerToken(req)
+		if !ok {
+			writeError(rw, "unauthorized", "Missing or invalid Authorization header.", 401)
+			return
+		}
+		if err := overrideBodyWithToken(req, t); err != nil {
+			if errors.Is(err, errTokenGameMismatch) {
+				writeError(rw, "unauthorized", "Token is not valid for this game.", 401)
+			} else {
+				writeError(rw, "malformed_body", "Unable to parse request body.", 400)
+			}
+			return
+		}
+	}
+
 	h.mux.ServeHTTP(rw, req)
 }
 
@@ -102,7 +170,7 @@ func (h *handler) handleIndex(rw http.ResponseWriter, req *http.Request) {
 		w1 := strings.ToLower(h.allWords[h.rand.Int63n(int64(len(h.allWords)))])
 		w2 := strings.ToLower(h.allWords[h.rand.Int63n(int64(len(h.allWords)))])
 		id := fmt.Sprintf("%s-%s", w1, w2)
-		if _, ok := h.games[id]; !ok {
+		if _, ok := h.store.Get(id); !ok {
 			break
 		}
 	}
@@ -119,12 +187,21 @@ func (h *handler) handleNewGame(rw http.ResponseWriter, req *http.Request) {
 		GameID   string   `json:"game_id"`
 		Words    []string `json:"words,omitempty"`
 		PrevSeed *Seed    `json:"prev_seed,omitempty"` // a string because of js number precision
+		Config   *Config  `json:"config,omitempty"`
 	}
 	err := json.NewDecoder(req.Body).Decode(&body)
 	if err != nil || body.GameID == "" {
 		writeError(rw, "malformed_body", "Unable to parse request body.", 400)
 		return
 	}
+	if body.Config != nil && body.Config.Mode != "" && !validModes[body.Config.Mode] {
+		writeError(rw, "bad_mode", fmt.Sprintf("Unknown mode %q.", body.Config.Mode), 400)
+		return
+	}
+	role := ""
+	if t, ok := bearerToken(req); ok {
+		role = t.Role
+	}
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -132,17 +209,33 @@ func (h *handler) handleNewGame(rw http.ResponseWriter, req *http.Request) {
 	// If the game already exists, make sure that the request includes
 	// the existing game's seed so a delayed request doesn't reset an
 	// existing game.
-	oldGame, ok := h.games[body.GameID]
+	oldGame, ok := h.store.Get(body.GameID)
 	if ok {
 		oldGame.mu.Lock()
 		defer oldGame.mu.Unlock()
 	}
 	if ok && (body.PrevSeed == nil || *body.PrevSeed != oldGame.Seed) {
-		writeJSON(rw, oldGame)
+		writeGameJSON(rw, oldGame, h.configs[body.GameID], role)
 		return
 	}
 
+	cfg := DefaultConfig
+	if body.Config != nil {
+		cfg = *body.Config
+		if cfg.Mode == "" {
+			cfg.Mode = "classic"
+		}
+	}
+
 	words := body.Words
+	if len(words) == 0 && len(cfg.Lists) > 0 {
+		var err error
+		words, err = h.wordsForLists(cfg.Lists)
+		if err != nil {
+			writeError(rw, "bad_word_list", err.Error(), 400)
+			return
+		}
+	}
 	if len(words) == 0 {
 		words = h.allWords
 	}
@@ -166,8 +259,14 @@ func (h *handler) handleNewGame(rw http.ResponseWriter, req *http.Request) {
 
 	g := &game
 	g.CreatedAt = time.Now()
-	h.games[body.GameID] = g
-	writeJSON(rw, g)
+	h.store.Put(body.GameID, g)
+
+	h.configs[body.GameID] = &cfg
+	if cfg.TurnSeconds > 0 {
+		go h.runTurnTimer(body.GameID, g, cfg.TurnSeconds)
+	}
+
+	writeGameJSON(rw, g, &cfg, role)
 }
 
 // POST /guess
@@ -182,28 +281,38 @@ func (h *handler) handleGuess(rw http.ResponseWriter, req *http.Request) {
 	}
 
 	err := json.NewDecoder(req.Body).Decode(&body)
-	if err != nil || body.GameID == "" || body.Team == 0 || body.PlayerID == "" {
+	if err != nil || body.GameID == "" || body.PlayerID == "" {
 		writeError(rw, "malformed_body", "Unable to parse request body.", 400)
 		return
 	}
+	if body.Team == 0 {
+		writeError(rw, "spectators_read_only", "Spectators can't guess.", 403)
+		return
+	}
 
-	h.mu.Lock()
-	g, ok := h.games[body.GameID]
-	h.mu.Unlock()
+	g, ok := h.store.Get(body.GameID)
 	if !ok {
 		writeError(rw, "not_found", "Game not found", 404)
 		return
 	}
 
 	g.mu.Lock()
-	defer g.mu.Unlock()
 	if body.Seed != g.Seed {
+		g.mu.Unlock()
 		writeError(rw, "bad_seed", "Request intended for a different game seed.", 400)
 		return
 	}
 
 	g.markSeen(body.PlayerID, body.Name, body.Team, time.Now())
 	g.guess(body.PlayerID, body.Name, body.Team, body.Index, time.Now())
+	// Keep the on-disk snapshot (if any) current with every game-state
+	// change, not just at creation, so a restart doesn't roll a game back
+	// to how it looked when it was first created. Put while still holding
+	// g.mu: snapshot marshals g's slices/maps, and releasing the lock
+	// first would let a concurrent request mutate them mid-marshal.
+	h.store.Put(body.GameID, g)
+	g.mu.Unlock()
+
 	writeJSON(rw, map[string]string{"status": "ok"})
 }
 
@@ -218,22 +327,24 @@ func (h *handler) handleEndTurn(rw http.ResponseWriter, req *http.Request) {
 	}
 
 	err := json.NewDecoder(req.Body).Decode(&body)
-	if err != nil || body.GameID == "" || body.Team == 0 || body.PlayerID == "" {
+	if err != nil || body.GameID == "" || body.PlayerID == "" {
 		writeError(rw, "malformed_body", "Unable to parse request body.", 400)
 		return
 	}
+	if body.Team == 0 {
+		writeError(rw, "spectators_read_only", "Spectators can't end a turn.", 403)
+		return
+	}
 
-	h.mu.Lock()
-	g, ok := h.games[body.GameID]
-	h.mu.Unlock()
+	g, ok := h.store.Get(body.GameID)
 	if !ok {
 		writeError(rw, "not_found", "Game not found", 404)
 		return
 	}
 
 	g.mu.Lock()
-	defer g.mu.Unlock()
 	if body.Seed != g.Seed {
+		g.mu.Unlock()
 		writeError(rw, "bad_seed", "Request intended for a different game seed.", 400)
 		return
 	}
@@ -245,6 +356,19 @@ func (h *handler) handleEndTurn(rw http.ResponseWriter, req *http.Request) {
 		PlayerID: body.PlayerID,
 		Name:     body.Name,
 	})
+	// Put while still holding g.mu: snapshot marshals g's slices/maps, and
+	// releasing the lock first would let a concurrent request mutate them
+	// mid-marshal.
+	h.store.Put(body.GameID, g)
+	g.mu.Unlock()
+
+	h.mu.Lock()
+	cfg := h.configs[body.GameID]
+	h.mu.Unlock()
+	if cfg != nil && cfg.TurnSeconds > 0 {
+		h.resetTurnClock(g, cfg.TurnSeconds)
+	}
+
 	writeJSON(rw, map[string]string{"status": "ok"})
 }
 
@@ -265,17 +389,15 @@ func (h *handler) handleChat(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	h.mu.Lock()
-	g, ok := h.games[body.GameID]
-	h.mu.Unlock()
+	g, ok := h.store.Get(body.GameID)
 	if !ok {
 		writeError(rw, "not_found", "Game not found", 404)
 		return
 	}
 
 	g.mu.Lock()
-	defer g.mu.Unlock()
 	if body.Seed != g.Seed {
+		g.mu.Unlock()
 		writeError(rw, "bad_seed", "Request intended for a different game seed.", 400)
 		return
 	}
@@ -288,10 +410,20 @@ func (h *handler) handleChat(rw http.ResponseWriter, req *http.Request) {
 		Name:     body.Name,
 		Message:  body.Message,
 	})
+	// Put while still holding g.mu: snapshot marshals g's slices/maps, and
+	// releasing the lock first would let a concurrent request mutate them
+	// mid-marshal.
+	h.store.Put(body.GameID, g)
+	g.mu.Unlock()
+
 	writeJSON(rw, map[string]string{"status": "ok"})
 }
 
 // POST /events
+// Long-polls for new events. Clients that support WebSockets should prefer
+// /ws, which streams events as they happen instead of holding a connection
+// open for up to 25 seconds at a time; this endpoint is kept for clients
+// that can't or haven't switched over yet.
 func (h *handler) handleEvents(rw http.ResponseWriter, req *http.Request) {
 	var body struct {
 		GameID    string `json:"game_id"`
@@ -300,6 +432,7 @@ func (h *handler) handleEvents(rw http.ResponseWriter, req *http.Request) {
 		Name      string `json:"name"`
 		Team      int    `json:"team"`
 		LastEvent int    `json:"last_event"`
+		MaxEvents int    `json:"max_events,omitempty"`
 	}
 
 	err := json.NewDecoder(req.Body).Decode(&body)
@@ -308,9 +441,7 @@ func (h *handler) handleEvents(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	h.mu.Lock()
-	g, ok := h.games[body.GameID]
-	h.mu.Unlock()
+	g, ok := h.store.Get(body.GameID)
 	if !ok {
 		writeError(rw, "not_found", "Game not found", 404)
 		return
@@ -321,7 +452,7 @@ func (h *handler) handleEvents(rw http.ResponseWriter, req *http.Request) {
 	if body.Seed != seed {
 		evts, _ := g.eventsSince(body.LastEvent)
 		g.mu.Unlock()
-		writeJSON(rw, GameUpdate{Seed: seed, Events: evts})
+		writeJSON(rw, GameUpdate{Seed: seed, Events: truncateEvents(evts, body.MaxEvents)})
 		return
 	}
 	g.markSeen(body.PlayerID, body.Name, body.Team, time.Now())
@@ -333,7 +464,7 @@ func (h *handler) handleEvents(rw http.ResponseWriter, req *http.Request) {
 	g.mu.Unlock()
 
 	if len(evts) > 0 {
-		writeJSON(rw, GameUpdate{Seed: seed, Events: evts})
+		writeJSON(rw, GameUpdate{Seed: seed, Events: truncateEvents(evts, body.MaxEvents)})
 		return
 	}
 
@@ -343,9 +474,7 @@ func (h *handler) handleEvents(rw http.ResponseWriter, req *http.Request) {
 	case <-ch:
 		// re-retrieve the game in case it was replaced
 		// while we were waiting for events.
-		h.mu.Lock()
-		g, ok := h.games[body.GameID]
-		h.mu.Unlock()
+		g, ok := h.store.Get(body.GameID)
 		if !ok {
 			writeError(rw, "not_found", "Game not found", 404)
 			return
@@ -358,7 +487,7 @@ func (h *handler) handleEvents(rw http.ResponseWriter, req *http.Request) {
 	case <-req.Context().Done():
 	case <-time.After(25 * time.Second):
 	}
-	writeJSON(rw, GameUpdate{Seed: seed, Events: evts})
+	writeJSON(rw, GameUpdate{Seed: seed, Events: truncateEvents(evts, body.MaxEvents)})
 }
 
 // POST /ping
@@ -381,9 +510,7 @@ func (h *handler) handlePing(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	h.mu.Lock()
-	g, ok := h.games[body.GameID]
-	h.mu.Unlock()
+	g, ok := h.store.Get(body.GameID)
 	if !ok {
 		writeError(rw, "not_found", "Game not found", 404)
 		return
@@ -404,23 +531,65 @@ type GameUpdate struct {
 	Events []Event `json:"events"`
 }
 
+// ageBuckets define the age histogram /stats reports; a game older than
+// every bucket's Max falls into "over_24h".
+var ageBuckets = []struct {
+	Label string
+	Max   time.Duration
+}{
+	{"under_5m", 5 * time.Minute},
+	{"under_30m", 30 * time.Minute},
+	{"under_1h", time.Hour},
+	{"under_6h", 6 * time.Hour},
+	{"under_24h", 24 * time.Hour},
+}
+
 func (h *handler) handleStats(rw http.ResponseWriter, req *http.Request) {
 	var players, games int
-	h.mu.Lock()
-	for _, g := range h.games {
+	listUsage := map[string]int{}
+	ageHistogram := map[string]int{}
+	now := time.Now()
+
+	h.store.Range(func(id string, g *Game) bool {
 		g.mu.Lock()
 		players += len(g.players)
 		if len(g.players) > 0 {
 			games++
 		}
+		age := now.Sub(g.CreatedAt)
 		g.mu.Unlock()
-	}
-	h.mu.Unlock()
+
+		bucket := "over_24h"
+		for _, b := range ageBuckets {
+			if age < b.Max {
+				bucket = b.Label
+				break
+			}
+		}
+		ageHistogram[bucket]++
+
+		h.mu.Lock()
+		cfg := h.configs[id]
+		h.mu.Unlock()
+		if cfg != nil {
+			for _, list := range cfg.Lists {
+				listUsage[list]++
+			}
+		}
+		return true
+	})
 
 	writeJSON(rw, struct {
-		ActiveGames   int `json:"active_games"`
-		ActivePlayers int `json:"active_players"`
-	}{ActiveGames: games, ActivePlayers: players})
+		ActiveGames   int            `json:"active_games"`
+		ActivePlayers int            `json:"active_players"`
+		ListUsage     map[string]int `json:"list_usage"`
+		AgeHistogram  map[string]int `json:"age_histogram"`
+	}{
+		ActiveGames:   games,
+		ActivePlayers: players,
+		ListUsage:     listUsage,
+		AgeHistogram:  ageHistogram,
+	})
 }
 
 func writeError(rw http.ResponseWriter, code, message string, statusCode int) {
@@ -431,6 +600,106 @@ func writeError(rw http.ResponseWriter, code, message string, statusCode int) {
 	}{Code: code, Message: message})
 }
 
+// writeGameJSON writes g the same way writeJSON(rw, g) would, but splices
+// in a top-level "config" field so reconnecting clients can pick the chosen
+// mode/timers back up without a separate request. cfg may be nil for games
+// created before config existed.
+//
+// It also reports spymaster_view: whether this response was produced for
+// a spymaster-privileged token (or a token with no role, or no token at
+// all, both treated as legacy callers with the full view). It does NOT
+// redact the color key: the
+// response still has to include the seed for operatives to keep playing,
+// and colorDistribution/NewState are plain deterministic functions of that
+// seed and the word list, both already present in the response, so any
+// client can recompute the same key locally regardless of what field names
+// get stripped out server-side. Deleting a guessed field name here would
+// just be a false assurance of confidentiality. Real enforcement needs the
+// color derivation itself - in the game package, not part of this checkout
+// - to stop being reproducible from data the client already has; until
+// then we report spymaster_view accurately instead of pretending to hide
+// anything.
+func writeGameJSON(rw http.ResponseWriter, g *Game, cfg *Config, role string) {
+	extras := map[string]json.RawMessage{}
+	if cfg != nil {
+		cj, err := json.Marshal(cfg)
+		if err != nil {
+			http.Error(rw, "unable to marshal response: "+err.Error(), 500)
+			return
+		}
+		extras["config"] = cj
+	}
+	spymasterView := role == "" || role == "spymaster"
+	if sj, err := json.Marshal(spymasterView); err == nil {
+		extras["spymaster_view"] = sj
+	}
+
+	fields, err := gameJSONWithExtrasMap(g, extras)
+	if err != nil {
+		http.Error(rw, "unable to marshal response: "+err.Error(), 500)
+		return
+	}
+
+	writeJSON(rw, fields)
+}
+
+// resetTurnClock pushes g's next forced end_turn out to turnSeconds from
+// now, overwriting any deadline already set. handleEndTurn calls this on
+// every real end_turn so runTurnTimer's next wakeup reflects the turn that
+// just started rather than the one that just ended.
+//
+// This is keyed by g's own pointer, not its game ID: handleNewGame reuses
+// the same game ID for a replacement game, and a *Game is never reused
+// across that replacement, so an old game's runTurnTimer cleaning up its
+// own deadline can never collide with a new game's deadline the way two
+// goroutines sharing an ID key could.
+func (h *handler) resetTurnClock(g *Game, turnSeconds int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.turnDeadlines[g] = time.Now().Add(time.Duration(turnSeconds) * time.Second)
+}
+
+// runTurnTimer forces an end_turn whenever g's turn clock, tracked in
+// h.turnDeadlines, actually expires, for modes that set one. It polls once
+// a second rather than sleeping for turnSeconds at a stretch, because
+// resetTurnClock can move the deadline at any time (handleEndTurn calls it
+// on every real end_turn); sleeping the full interval would force an extra
+// end_turn partway into whichever turn was already under way when the
+// previous one ended. It stops once the game at gameID is replaced or
+// removed.
+func (h *handler) runTurnTimer(gameID string, g *Game, turnSeconds int) {
+	h.resetTurnClock(g, turnSeconds)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.mu.Lock()
+		current, ok := h.store.Get(gameID)
+		deadline := h.turnDeadlines[g]
+		h.mu.Unlock()
+		if !ok || current != g {
+			h.mu.Lock()
+			delete(h.turnDeadlines, g)
+			h.mu.Unlock()
+			return
+		}
+		if time.Now().Before(deadline) {
+			continue
+		}
+
+		g.mu.Lock()
+		g.addEvent(Event{Type: "timer_expired"})
+		g.addEvent(Event{Type: "end_turn"})
+		// Put while still holding g.mu: snapshot marshals g's slices/maps,
+		// and releasing the lock first would let a concurrent request
+		// mutate them mid-marshal.
+		h.store.Put(gameID, g)
+		g.mu.Unlock()
+		h.resetTurnClock(g, turnSeconds)
+	}
+}
+
 func writeJSON(rw http.ResponseWriter, resp interface{}) {
 	j, err := json.Marshal(resp)
 	if err != nil {
@@ -0,0 +1,57 @@
+package gameapi
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// GET /list
+// Returns active games whose config marks them public, so a client can
+// discover a game to join without the ID being shared out of band.
+func (h *handler) handleList(rw http.ResponseWriter, req *http.Request) {
+	type teamEntry struct {
+		Team  int      `json:"team"`
+		Names []string `json:"names"`
+	}
+	type listEntry struct {
+		ID          string      `json:"id"`
+		CreatedAt   time.Time   `json:"created_at"`
+		PlayerCount int         `json:"player_count"`
+		Teams       []teamEntry `json:"teams"`
+		Mode        string      `json:"mode"`
+	}
+
+	var entries []listEntry
+	h.store.Range(func(id string, g *Game) bool {
+		h.mu.Lock()
+		cfg := h.configs[id]
+		h.mu.Unlock()
+		if cfg == nil || !cfg.Public {
+			return true
+		}
+
+		g.mu.Lock()
+		names := map[int][]string{}
+		for _, p := range g.players {
+			names[p.Team] = append(names[p.Team], p.Name)
+		}
+		entry := listEntry{
+			ID:          id,
+			CreatedAt:   g.CreatedAt,
+			PlayerCount: len(g.players),
+			Mode:        cfg.Mode,
+		}
+		g.mu.Unlock()
+
+		for _, team := range []int{1, 2} {
+			sort.Strings(names[team])
+			entry.Teams = append(entry.Teams, teamEntry{Team: team, Names: names[team]})
+		}
+		entries = append(entries, entry)
+		return true
+	})
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	writeJSON(rw, entries)
+}
@@ -0,0 +1,118 @@
+package gameapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// truncateEvents caps evts at max entries, for callers that page through a
+// long history instead of returning everything unboundedly. max <= 0 means
+// no cap.
+func truncateEvents(evts []Event, max int) []Event {
+	if max > 0 && len(evts) > max {
+		return evts[:max]
+	}
+	return evts
+}
+
+func queryInt(req *http.Request, key string) int {
+	raw := req.URL.Query().Get(key)
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// GET /replay?game_id=...&max_events=...
+// Returns everything needed to re-render a game after the fact: its seed,
+// board/word data, config, and full event log.
+func (h *handler) handleReplay(rw http.ResponseWriter, req *http.Request) {
+	gameID := req.URL.Query().Get("game_id")
+	if gameID == "" {
+		writeError(rw, "malformed_body", "game_id is required.", 400)
+		return
+	}
+
+	g, ok := h.store.Get(gameID)
+	if !ok {
+		writeError(rw, "not_found", "Game not found", 404)
+		return
+	}
+
+	g.mu.Lock()
+	evts, _ := g.eventsSince(0)
+	g.mu.Unlock()
+	evts = truncateEvents(evts, queryInt(req, "max_events"))
+
+	h.mu.Lock()
+	cfg := h.configs[gameID]
+	h.mu.Unlock()
+
+	extras := map[string]json.RawMessage{}
+	if cfg != nil {
+		if cj, err := json.Marshal(cfg); err == nil {
+			extras["config"] = cj
+		}
+	}
+	if ej, err := json.Marshal(evts); err == nil {
+		extras["events"] = ej
+	}
+
+	fields, err := gameJSONWithExtrasMap(g, extras)
+	if err != nil {
+		http.Error(rw, "unable to marshal response: "+err.Error(), 500)
+		return
+	}
+	writeJSON(rw, fields)
+}
+
+// GET /export?game_id=...&format=json|ndjson&max_events=...
+// Streams a game's event log for archival. ndjson writes one event per
+// line, which is easier for external tools to tail or resume mid-stream
+// than a single JSON array; max_events bounds either form for clients
+// catching up on a long game.
+func (h *handler) handleExport(rw http.ResponseWriter, req *http.Request) {
+	gameID := req.URL.Query().Get("game_id")
+	if gameID == "" {
+		writeError(rw, "malformed_body", "game_id is required.", 400)
+		return
+	}
+
+	g, ok := h.store.Get(gameID)
+	if !ok {
+		writeError(rw, "not_found", "Game not found", 404)
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "ndjson" {
+		writeError(rw, "bad_format", "format must be json or ndjson.", 400)
+		return
+	}
+
+	g.mu.Lock()
+	evts, _ := g.eventsSince(0)
+	g.mu.Unlock()
+	evts = truncateEvents(evts, queryInt(req, "max_events"))
+
+	if format == "json" {
+		writeJSON(rw, evts)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(rw)
+	for _, evt := range evts {
+		if err := enc.Encode(evt); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package gameapi
+
+import "testing"
+
+func TestTruncateEvents(t *testing.T) {
+	evts := []Event{{Type: "a"}, {Type: "b"}, {Type: "c"}}
+
+	if got := truncateEvents(evts, 0); len(got) != 3 {
+		t.Fatalf("truncateEvents(evts, 0) = %d events, want 3 (no cap)", len(got))
+	}
+	if got := truncateEvents(evts, 2); len(got) != 2 || got[0].Type != "a" || got[1].Type != "b" {
+		t.Fatalf("truncateEvents(evts, 2) = %+v, want the first 2 events", got)
+	}
+	if got := truncateEvents(evts, 10); len(got) != 3 {
+		t.Fatalf("truncateEvents(evts, 10) = %d events, want all 3 when max exceeds len", len(got))
+	}
+}
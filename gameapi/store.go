@@ -0,0 +1,182 @@
+package gameapi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// GameStore abstracts how active games are kept, so the HTTP layer doesn't
+// need to know whether they live purely in memory or somewhere that
+// survives a restart.
+type GameStore interface {
+	Get(id string) (*Game, bool)
+	Put(id string, g *Game)
+	Delete(id string)
+	Range(fn func(id string, g *Game) bool)
+}
+
+// memoryStore is today's behavior: games live only as long as the process
+// does.
+type memoryStore struct {
+	mu    sync.Mutex
+	games map[string]*Game
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{games: make(map[string]*Game)}
+}
+
+func (s *memoryStore) Get(id string) (*Game, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	return g, ok
+}
+
+func (s *memoryStore) Put(id string, g *Game) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.games[id] = g
+}
+
+func (s *memoryStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.games, id)
+}
+
+func (s *memoryStore) Range(fn func(id string, g *Game) bool) {
+	s.mu.Lock()
+	snapshot := make(map[string]*Game, len(s.games))
+	for id, g := range s.games {
+		snapshot[id] = g
+	}
+	s.mu.Unlock()
+
+	for id, g := range snapshot {
+		if !fn(id, g) {
+			return
+		}
+	}
+}
+
+// fileStore snapshots every Put to dir/<id>.json, so an active game survives
+// a process restart, and reloads whatever is on disk on startup. It keeps
+// an in-memory copy for reads so /guess and friends don't hit disk on every
+// request.
+type fileStore struct {
+	dir       string
+	configFor func(id string) *Config
+	mem       *memoryStore
+}
+
+// newFileStore loads any games already snapshotted under dir. configFor
+// looks up a game's Config (kept separately on handler, not on GameStore)
+// so it can be included in each snapshot.
+func newFileStore(dir string, configFor func(id string) *Config) (*fileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	fs := &fileStore{dir: dir, configFor: configFor, mem: newMemoryStore()}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue // skip a snapshot we can't read rather than failing startup
+		}
+		var g Game
+		if err := json.Unmarshal(data, &g); err != nil {
+			continue // skip a snapshot we can't parse rather than failing startup
+		}
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		fs.mem.Put(id, &g)
+	}
+	return fs, nil
+}
+
+func (fs *fileStore) Get(id string) (*Game, bool) {
+	return fs.mem.Get(id)
+}
+
+// Put marshals g for the on-disk snapshot, so callers mutating an existing
+// g concurrently with other requests (guess, end-turn, chat, the turn
+// timer) must hold g.mu across the call - releasing it first would let
+// another request mutate g's slices/maps while this marshal is reading
+// them.
+func (fs *fileStore) Put(id string, g *Game) {
+	fs.mem.Put(id, g)
+	fs.snapshot(id, g)
+}
+
+func (fs *fileStore) Delete(id string) {
+	fs.mem.Delete(id)
+	os.Remove(fs.path(id))
+}
+
+func (fs *fileStore) Range(fn func(id string, g *Game) bool) {
+	fs.mem.Range(fn)
+}
+
+func (fs *fileStore) path(id string) string {
+	return filepath.Join(fs.dir, id+".json")
+}
+
+// snapshot writes g, plus its Config if one is registered, to dir/<id>.json.
+// It's best-effort: a failed write is dropped rather than surfaced to the
+// player who happened to trigger it, since addEvent itself can't fail.
+func (fs *fileStore) snapshot(id string, g *Game) {
+	extras := map[string]json.RawMessage{}
+	if fs.configFor != nil {
+		if cfg := fs.configFor(id); cfg != nil {
+			if cj, err := json.Marshal(cfg); err == nil {
+				extras["config"] = cj
+			}
+		}
+	}
+	data, err := gameJSONWithExtras(g, extras)
+	if err != nil {
+		return
+	}
+	os.WriteFile(fs.path(id), data, 0644)
+}
+
+// gameJSONWithExtrasMap marshals g the way writeJSON(rw, g) would, unpacks
+// it into a field map, and splices in additional top-level fields. Shared
+// by writeGameJSON (the /new-game response) and fileStore.snapshot (the
+// on-disk copy), both of which need to attach a Config that doesn't live
+// on Game itself. Returning the map, rather than already-marshaled bytes,
+// lets a caller add or remove individual fields before the final encode.
+func gameJSONWithExtrasMap(g *Game, extras map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	gj, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(gj, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range extras {
+		fields[k] = v
+	}
+	return fields, nil
+}
+
+// gameJSONWithExtras is gameJSONWithExtrasMap followed by a final encode,
+// for callers that don't need to touch individual fields.
+func gameJSONWithExtras(g *Game, extras map[string]json.RawMessage) ([]byte, error) {
+	fields, err := gameJSONWithExtrasMap(g, extras)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,86 @@
+package gameapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryStoreGetPutDelete(t *testing.T) {
+	s := newMemoryStore()
+
+	if _, ok := s.Get("g1"); ok {
+		t.Fatal("Get found an entry before any Put")
+	}
+
+	g := &Game{}
+	s.Put("g1", g)
+	got, ok := s.Get("g1")
+	if !ok || got != g {
+		t.Fatalf("Get after Put = %v, %v, want %v, true", got, ok, g)
+	}
+
+	s.Delete("g1")
+	if _, ok := s.Get("g1"); ok {
+		t.Fatal("Get found an entry after Delete")
+	}
+}
+
+func TestMemoryStoreRange(t *testing.T) {
+	s := newMemoryStore()
+	s.Put("a", &Game{})
+	s.Put("b", &Game{})
+
+	seen := map[string]bool{}
+	s.Range(func(id string, g *Game) bool {
+		seen[id] = true
+		return true
+	})
+	if !seen["a"] || !seen["b"] {
+		t.Fatalf("Range visited %v, want both a and b", seen)
+	}
+}
+
+func TestMemoryStoreRangeStopsEarly(t *testing.T) {
+	s := newMemoryStore()
+	s.Put("a", &Game{})
+	s.Put("b", &Game{})
+
+	calls := 0
+	s.Range(func(id string, g *Game) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Fatalf("Range made %d calls after returning false, want 1", calls)
+	}
+}
+
+func TestFileStorePersistsAndReloads(t *testing.T) {
+	dir := t.TempDir()
+
+	fs, err := newFileStore(dir, func(id string) *Config { return nil })
+	if err != nil {
+		t.Fatalf("newFileStore: %v", err)
+	}
+
+	g := &Game{}
+	fs.Put("g1", g)
+
+	if _, err := os.Stat(filepath.Join(dir, "g1.json")); err != nil {
+		t.Fatalf("snapshot file missing after Put: %v", err)
+	}
+
+	reloaded, err := newFileStore(dir, func(id string) *Config { return nil })
+	if err != nil {
+		t.Fatalf("newFileStore (reload): %v", err)
+	}
+	if _, ok := reloaded.Get("g1"); !ok {
+		t.Fatal("reloaded fileStore didn't pick up the snapshot written by the original")
+	}
+
+	fs.Delete("g1")
+	if _, err := os.Stat(filepath.Join(dir, "g1.json")); !os.IsNotExist(err) {
+		t.Fatalf("snapshot file still present after Delete: %v", err)
+	}
+}
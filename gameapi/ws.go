@@ -0,0 +1,121 @@
+package gameapi
+
+import (
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// wsHandshake is the first frame a client must send once the WebSocket
+// upgrade completes. It carries the same identifying fields as the /events
+// long-poll body so the two transports can share the same bookkeeping.
+type wsHandshake struct {
+	GameID    string `json:"game_id"`
+	Seed      Seed   `json:"seed"`
+	PlayerID  string `json:"player_id"`
+	Name      string `json:"name"`
+	Team      int    `json:"team"`
+	LastEvent int    `json:"last_event"`
+	MaxEvents int    `json:"max_events,omitempty"`
+}
+
+// wsFrame is every message the server writes to a /ws connection after the
+// handshake. Events carries newly appended events; SeedChanged is set
+// instead, on its own, when handleNewGame has replaced the underlying game.
+type wsFrame struct {
+	Seed        Seed    `json:"seed,omitempty"`
+	SeedChanged Seed    `json:"seed_changed,omitempty"`
+	Events      []Event `json:"events,omitempty"`
+	Ping        bool    `json:"ping,omitempty"`
+}
+
+const wsKeepalive = 15 * time.Second
+
+// GET /ws?token=...
+// This is the push-based replacement for the /events long-poll: the client
+// upgrades, sends a wsHandshake frame, and from then on receives a wsFrame
+// every time new events land in the game, without needing to reconnect
+// every 25 seconds. /events is left in place for clients that haven't
+// switched over yet; both are driven by the same Game.eventsSince/notifyAll
+// plumbing, so a guess made over HTTP shows up on a WebSocket client and
+// vice versa.
+//
+// /ws can't go through ServeHTTP's authedPaths/overrideBodyWithToken path
+// the way /events does: there's no request body to rewrite before the
+// upgrade, and the handshake carrying player_id/team only arrives as a
+// frame afterward. The token travels as a query parameter on the upgrade
+// request instead, and hs.PlayerID/hs.Team are overridden from it exactly
+// like overrideBodyWithToken does for the HTTP endpoints, rather than
+// trusted from the handshake frame.
+func (h *handler) handleWS(ws *websocket.Conn) {
+	defer ws.Close()
+
+	t, ok := verifyAuthToken(ws.Request().URL.Query().Get("token"))
+	if !ok {
+		return
+	}
+
+	var hs wsHandshake
+	if err := websocket.JSON.Receive(ws, &hs); err != nil || hs.GameID == "" {
+		return
+	}
+	if hs.GameID != t.GameID {
+		return
+	}
+	hs.PlayerID = t.PlayerID
+	hs.Team = t.Team
+
+	g, ok := h.store.Get(hs.GameID)
+	if !ok {
+		return
+	}
+
+	lastEvent := hs.LastEvent
+	keepalive := time.NewTicker(wsKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		g.mu.Lock()
+		seed := g.Seed
+		if seed != hs.Seed {
+			g.mu.Unlock()
+			if err := websocket.JSON.Send(ws, wsFrame{SeedChanged: seed}); err != nil {
+				return
+			}
+			hs.Seed = seed
+			lastEvent = 0
+			continue
+		}
+		g.markSeen(hs.PlayerID, hs.Name, hs.Team, time.Now())
+		evts, ch := g.eventsSince(lastEvent)
+		g.mu.Unlock()
+
+		if len(evts) > 0 {
+			evts = truncateEvents(evts, hs.MaxEvents)
+			if err := websocket.JSON.Send(ws, wsFrame{Seed: seed, Events: evts}); err != nil {
+				return
+			}
+			lastEvent += len(evts)
+			continue
+		}
+
+		select {
+		case <-ch:
+			// Something changed; loop around and pick it up.
+		case <-keepalive.C:
+			g.mu.Lock()
+			g.markSeen(hs.PlayerID, hs.Name, hs.Team, time.Now())
+			g.mu.Unlock()
+			if err := websocket.JSON.Send(ws, wsFrame{Ping: true}); err != nil {
+				return
+			}
+		}
+
+		// The game may have been replaced entirely while we were
+		// waiting; re-retrieve it the same way handleEvents does.
+		g, ok = h.store.Get(hs.GameID)
+		if !ok {
+			return
+		}
+	}
+}